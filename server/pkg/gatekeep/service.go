@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -22,8 +23,45 @@ type (
 		events eventManager
 
 		logger *zap.Logger
+
+		// locks indexes the resource key a given (acquired) lock lives under
+		// so we don't have to scan the whole store to find it again (refresh,
+		// release, ...)
+		locks map[uint64]string
+
+		// minLeaseTTL is the smallest LeaseTTL seen across acquired locks; it
+		// drives how often Watch ticks cleanupStore
+		minLeaseTTL time.Duration
+
+		// batchGroups tracks the sibling locks of every acquired LockMany batch,
+		// keyed by batchID, so releasing (or losing) any single member releases
+		// the rest of the group
+		batchGroups map[uint64]map[uint64]string // batchID -> lockID -> resource
+
+		// quorum is how many replicas (svc.store itself plus svc.replicas) must
+		// confirm a CAS write before an acquisition is considered final;
+		// ignored for a plain (non-distributed) store
+		quorum int
+
+		// replicas holds independent DistributedStore backends that
+		// casWithQuorum also CASes against, alongside the primary store passed
+		// to New, to confirm a write landed on a majority of replicas; unused
+		// unless paired with WithQuorum(>1)
+		replicas []DistributedStore
+
+		// policy picks how doQueued chooses its next promotion set; see
+		// PolicyFIFO/PolicyReaderPreferring/PolicyFairReaderPreferring
+		policy SchedulePolicy
+
+		// maxReaderBurst and maxWriterWait bound writer starvation under
+		// PolicyFairReaderPreferring; see doQueued
+		maxReaderBurst int
+		maxWriterWait  time.Duration
 	}
 
+	// Option configures optional service behavior at New
+	Option func(*service)
+
 	EventListener func(evt Event)
 	Event         struct {
 		Kind ebEvent
@@ -46,16 +84,48 @@ type (
 		Await     time.Duration
 		ExpiresIn time.Duration
 
+		// LeaseTTL, if set, is how long an acquired lock is valid for before it's
+		// considered abandoned by a crashed/unresponsive caller. Callers that are
+		// still alive are expected to call Refresh before the lease runs out.
+		LeaseTTL time.Duration
+
+		// batchID links sibling constraints submitted together via LockMany so
+		// doQueued can promote (or release) them as a single, all-or-nothing unit
+		batchID uint64
+
+		// Source identifies where this constraint was requested from (e.g. a
+		// "file.go:123" caller tag); purely informational, surfaced by TopLocks
+		Source string
+
+		// Owner identifies the process/node requesting the lock; if left empty
+		// it defaults to the process-wide identity set via SetOwner
+		Owner string
+
 		queuedAt time.Time
 	}
 
 	queue struct {
 		queue []Constraint
+
+		// readersSinceWriterHead counts queued readers appended while a
+		// writer constraint sits at the head of this queue; it's how
+		// PolicyFairReaderPreferring notices a writer is being skipped over
+		readersSinceWriterHead int
+
+		// writerHeadSince is when the current head became a writer
+		// constraint, used alongside maxWriterWait by
+		// PolicyFairReaderPreferring
+		writerHeadSince time.Time
 	}
 
 	queueManager struct {
 		mux    sync.Mutex
 		queues map[string]*queue
+
+		// batches indexes the queued constraints of every pending LockMany call
+		// by batchID, mirroring what's queued across the (possibly several)
+		// per-resource queues in queues
+		batches map[uint64][]Constraint
 	}
 
 	store interface {
@@ -64,6 +134,66 @@ type (
 		DeleteValue(ctx context.Context, key string) error
 	}
 
+	// Iterator is an optional store capability: a store that implements it can
+	// be scanned key-by-key, which is what TopLocks uses to walk the whole
+	// keyspace instead of relying on svc.locks (which only ever covers locks
+	// acquired by this node). It's checked for with a type assertion the same
+	// way DistributedStore is, so a plain store isn't forced to implement a
+	// scan it may not be able to support efficiently.
+	Iterator interface {
+		// Iterate calls fn for every key under prefix (all keys if prefix is
+		// empty)
+		Iterate(ctx context.Context, prefix string, fn func(key string, v []byte) error) error
+	}
+
+	// TopLocksOptions configures TopLocks
+	TopLocksOptions struct {
+		// Count caps the number of locks returned; 0 means unbounded
+		Count int
+
+		// IncludeQueued also surfaces constraints still waiting in the queue
+		IncludeQueued bool
+
+		// IncludeStale also surfaces (and flags) locks whose lease has expired
+		// but that a cleanupStore tick hasn't caught up with yet
+		IncludeStale bool
+
+		// ResourcePrefix, if set, restricts the scan to resources starting with it
+		ResourcePrefix string
+	}
+
+	// DistributedStore extends store with what's needed to coordinate the
+	// lock state of several gatekeep nodes against one shared backend (etcd,
+	// Consul, ...) instead of each node only ever seeing its own in-memory view
+	DistributedStore interface {
+		store
+
+		// CompareAndSwap atomically replaces expect with new for key, returning
+		// (false, nil) rather than an error if the current value didn't match
+		// expect (a nil/empty expect means "key must not exist yet")
+		CompareAndSwap(ctx context.Context, key string, expect, new []byte) (bool, error)
+
+		// Watch streams PUT/DELETE notifications for every key under keyPrefix
+		Watch(ctx context.Context, keyPrefix string) (<-chan StoreEvent, error)
+
+		// Lease requests a TTL-bound lease from the backend; the returned ID is
+		// opaque to gatekeep and is only meaningful to the backing store
+		Lease(ctx context.Context, ttl time.Duration) (LeaseID, error)
+	}
+
+	// StoreEvent is a single change notification from a DistributedStore's
+	// Watch stream
+	StoreEvent struct {
+		Key   string
+		Value []byte
+		Type  StoreEventType
+	}
+
+	StoreEventType string
+
+	// LeaseID identifies a lease handed out by a DistributedStore's Lease call
+	LeaseID string
+
 	Lock struct {
 		ID        uint64    `json:"id,string"`
 		UserID    uint64    `json:"userID,string"`
@@ -75,12 +205,37 @@ type (
 
 		LockDuration time.Duration `json:"lockDuration"`
 		AcquiredAt   time.Time     `json:"acquiredAt"`
+
+		// LeaseTTL/LeaseExpiresAt implement the MinIO-style refresh/heartbeat
+		// semantics: a lock acquired with a LeaseTTL must be refreshed by its
+		// owner before LeaseExpiresAt or it's considered zombie and cleaned up.
+		// A zero LeaseExpiresAt means the lock never expires on its own.
+		LeaseTTL       time.Duration `json:"leaseTTL,omitempty"`
+		LeaseExpiresAt time.Time     `json:"leaseExpiresAt,omitempty"`
+
+		// BatchID, if non-zero, identifies the LockMany group this lock was
+		// acquired (or queued) as part of; releasing or unqueueing one member
+		// releases/unqueues every other member of the group
+		BatchID uint64 `json:"batchID,string,omitempty"`
+
+		// Source/Owner carry through from the originating Constraint for lock
+		// forensics; see TopLocks
+		Source string `json:"source,omitempty"`
+		Owner  string `json:"owner,omitempty"`
+
+		// Stale is set by TopLocks for locks whose LeaseExpiresAt has already
+		// passed; it's a view-only annotation, never persisted to the store
+		Stale bool `json:"stale,omitempty"`
 	}
 
 	ebEvent int
 
 	LockState string
 	Operation string
+
+	// SchedulePolicy picks how doQueued chooses which queued constraints to
+	// promote once a resource's locks change
+	SchedulePolicy string
 )
 
 const (
@@ -89,10 +244,11 @@ const (
 )
 
 const (
-	lockStateNil    LockState = ""
-	lockStateLocked LockState = "locked"
-	lockStateFailed LockState = "failed"
-	lockStateQueued LockState = "queued"
+	lockStateNil      LockState = ""
+	lockStateLocked   LockState = "locked"
+	lockStateFailed   LockState = "failed"
+	lockStateQueued   LockState = "queued"
+	lockStateReleased LockState = "released"
 )
 
 const (
@@ -100,6 +256,37 @@ const (
 	EbEventLockReleased
 )
 
+const (
+	StoreEventPut    StoreEventType = "put"
+	StoreEventDelete StoreEventType = "delete"
+)
+
+const (
+	// PolicyFIFO promotes the head of the queue: a single writer, or the
+	// contiguous run of readers starting at the head
+	PolicyFIFO SchedulePolicy = "fifo"
+
+	// PolicyReaderPreferring promotes every queued reader for a resource
+	// with no active writer, regardless of where they sit in the queue,
+	// maximizing read throughput at the cost of writer starvation
+	PolicyReaderPreferring SchedulePolicy = "reader-preferring"
+
+	// PolicyFairReaderPreferring behaves like PolicyReaderPreferring until a
+	// writer at the head has waited longer than maxWriterWait or been
+	// skipped by more than maxReaderBurst readers, at which point it stops
+	// promoting new readers so that writer can finally run
+	PolicyFairReaderPreferring SchedulePolicy = "fair-reader-preferring"
+)
+
+// leaseCheckFraction controls how often Watch ticks cleanupStore relative to
+// the smallest configured LeaseTTL; we want a few chances to catch an expired
+// lease before it goes stale for long.
+const leaseCheckFraction = 4
+
+// defaultStoreGcInterval is used by Watch until at least one lock with a
+// LeaseTTL has been acquired
+const defaultStoreGcInterval = time.Minute * 5
+
 var (
 	gSvc *service
 
@@ -109,22 +296,88 @@ var (
 	}
 )
 
+// WithQuorum sets how many replicas (the primary DistributedStore passed to
+// New plus whatever WithReplicas adds) must confirm a CAS write before a lock
+// acquisition/release is considered final; it's ignored when the service
+// isn't given a DistributedStore. n is clamped down to 1+len(replicas) at New
+// if it asks for more confirmations than there are replicas to give them.
+func WithQuorum(n int) Option {
+	return func(svc *service) {
+		svc.quorum = n
+	}
+}
+
+// WithReplicas adds independent DistributedStore backends that casWithQuorum
+// confirms a CAS write against, alongside the primary store passed to New.
+// Pair this with WithQuorum(>1) to require a majority of replicas agree
+// before a write counts, mirroring the dsync-style quorum model; without it,
+// Quorum has nothing beyond the primary store to confirm against.
+func WithReplicas(rr ...DistributedStore) Option {
+	return func(svc *service) {
+		svc.replicas = append(svc.replicas, rr...)
+	}
+}
+
+// WithPolicy picks how doQueued chooses its next promotion set; it defaults
+// to PolicyFIFO
+func WithPolicy(p SchedulePolicy) Option {
+	return func(svc *service) {
+		svc.policy = p
+	}
+}
+
+// WithMaxReaderBurst bounds writer starvation under
+// PolicyFairReaderPreferring: once a writer at the head of a queue has been
+// skipped by more than n readers, no more readers are promoted ahead of it
+func WithMaxReaderBurst(n int) Option {
+	return func(svc *service) {
+		svc.maxReaderBurst = n
+	}
+}
+
+// WithMaxWriterWait bounds writer starvation under
+// PolicyFairReaderPreferring: once a writer has sat at the head of a queue
+// longer than d, no more readers are promoted ahead of it
+func WithMaxWriterWait(d time.Duration) Option {
+	return func(svc *service) {
+		svc.maxWriterWait = d
+	}
+}
+
 // New creates a DAL service with the primary connection
 //
 // It needs an established and working connection to the primary store
-func New(log *zap.Logger, s store) (*service, error) {
+func New(log *zap.Logger, s store, oo ...Option) (*service, error) {
 	svc := &service{
 		mux:    sync.RWMutex{},
 		logger: log,
 		store:  s,
 
 		queueManager: &queueManager{
-			mux:    sync.Mutex{},
-			queues: make(map[string]*queue),
+			mux:     sync.Mutex{},
+			queues:  make(map[string]*queue),
+			batches: make(map[uint64][]Constraint),
 		},
 
 		events: &inMemBus{},
+
+		locks:       make(map[uint64]string),
+		batchGroups: make(map[uint64]map[uint64]string),
+
+		quorum: 1,
+		policy: PolicyFIFO,
+	}
+
+	for _, o := range oo {
+		o(svc)
+	}
+
+	if maxQuorum := len(svc.replicas) + 1; svc.quorum > maxQuorum {
+		svc.logger.Warn("gatekeep: quorum exceeds configured replicas, clamping",
+			zap.Int("quorum", svc.quorum), zap.Int("replicas", len(svc.replicas)))
+		svc.quorum = maxQuorum
 	}
+
 	return svc, nil
 }
 
@@ -151,6 +404,28 @@ func SetGlobal(svc *service, err error) {
 	gSvc = svc
 }
 
+// processOwner is the process/node identity stamped onto locks whose
+// Constraint didn't set its own Owner
+var processOwner string
+
+// SetOwner sets the process/node identity used for lock attribution
+//
+// Call this once during setup (alongside New/SetGlobal) so TopLocks can
+// tell operators which node is holding what, e.g. SetOwner(hostname)
+func SetOwner(owner string) {
+	processOwner = owner
+}
+
+// ownerOrDefault falls back to the process-wide owner when a Constraint
+// didn't set its own
+func ownerOrDefault(owner string) string {
+	if owner != "" {
+		return owner
+	}
+
+	return processOwner
+}
+
 // Lock attempts to acquire a lock conforming to the given constraints
 //
 // If a lock can't be acquired the request will either be queued or fail
@@ -212,6 +487,8 @@ func (svc *service) Lock(ctx context.Context, c Constraint) (l Lock, err error)
 		Resource:  c.Resource,
 		Operation: c.Operation,
 		State:     lockStateQueued,
+		Source:    c.Source,
+		Owner:     ownerOrDefault(c.Owner),
 	}
 
 	if err != nil {
@@ -256,211 +533,1002 @@ func (svc *service) Unlock(ctx context.Context, c Constraint) (err error) {
 		Lock: lock,
 	})
 
+	if lock.BatchID > 0 {
+		if exists == lockStateLocked {
+			err = svc.releaseBatchGroup(ctx, lock.BatchID, lock.ID)
+		} else if exists == lockStateQueued {
+			err = svc.releaseQueuedBatch(ctx, lock.BatchID, lock.ID)
+		}
+	}
+
 	return
 }
 
-// ProbeLock returns the current state of the lock
-func (svc *service) ProbeLock(ctx context.Context, c Constraint, ref uint64) (state LockState, err error) {
+// LockMany attempts to acquire every constraint in cc as a single atomic unit
+//
+// Either every lock in the set is acquired or none are: callers that need to
+// hold several resources at once (e.g. a bulk-delete touching N records)
+// should use this instead of calling Lock in a loop, which deadlocks
+// trivially the moment two callers request overlapping resources in opposite
+// orders. Constraints are sorted into a canonical order before anything is
+// probed so two overlapping batches always contend for the same resource
+// first, and if the whole set can't be acquired right away it's queued as a
+// single group instead of partially acquired.
+func (svc *service) LockMany(ctx context.Context, cc []Constraint) (ll []Lock, err error) {
+	if len(cc) == 0 {
+		return
+	}
+
 	svc.mux.Lock()
 	defer svc.mux.Unlock()
 
-	tt, err := svc.probeResource(ctx, c.Resource)
+	cc = append([]Constraint(nil), cc...)
+	canonicalSortConstraints(cc)
+
+	var acquirable bool
+	acquirable, err = svc.probeAllAcquirable(ctx, cc)
 	if err != nil {
 		return
 	}
 
-	for _, t := range tt {
-		if t.ID == ref {
-			return t.State, nil
+	if acquirable {
+		batchID := nextID()
+		ll = make([]Lock, 0, len(cc))
+		acquired := make([]Lock, 0, len(cc))
+
+		for _, c := range cc {
+			c.batchID = batchID
+			l, lErr := svc.acquireLock(ctx, c)
+			if lErr != nil {
+				l.State = lockStateFailed
+				ll = append(ll, l)
+				err = lErr
+				break
+			}
+
+			l.State = lockStateLocked
+			ll = append(ll, l)
+			acquired = append(acquired, l)
 		}
-	}
 
-	return
-}
+		if err != nil {
+			// Either every constraint in cc is acquired or none are: a
+			// member failing after probeAllAcquirable said the whole batch
+			// was acquirable (e.g. a cross-node CAS race) must not leave
+			// the rest of the batch holding their locks.
+			for _, l := range acquired {
+				if rErr := svc.releaseLock(ctx, Constraint{Resource: l.Resource}, l.ID); rErr != nil {
+					svc.logger.Error("failed to roll back partially acquired batch",
+						zap.Uint64("lockID", l.ID), zap.String("resource", l.Resource), zap.Error(rErr))
+				}
+			}
+			delete(svc.batchGroups, batchID)
 
-func (svc *service) ProbeResource(ctx context.Context, r string) (tt []Lock, err error) {
-	svc.mux.RLock()
-	defer svc.mux.RUnlock()
+			for i := range ll {
+				ll[i].State = lockStateFailed
+			}
 
-	return svc.probeResource(ctx, r)
-}
+			for i := len(ll); i < len(cc); i++ {
+				c := cc[i]
+				ll = append(ll, Lock{Resource: c.Resource, Operation: c.Operation, UserID: c.UserID, State: lockStateFailed})
+			}
+		}
 
-func (svc *service) Subscribe(listener EventListener) int {
-	if svc.events == nil {
-		panic("events not initialized")
+		return
 	}
 
-	return svc.events.Subscribe(listener)
-}
-
-func (svc *service) Unsubscribe(id int) {
-	if svc.events == nil {
-		panic("events not initialized")
+	canAwait := true
+	for _, c := range cc {
+		canAwait = canAwait && c.Await > 0
 	}
 
-	svc.events.Unsubscribe(id)
-}
+	if !canAwait {
+		ll = make([]Lock, len(cc))
+		for i, c := range cc {
+			ll[i] = Lock{Resource: c.Resource, Operation: c.Operation, UserID: c.UserID, State: lockStateFailed}
+		}
+		return
+	}
 
-func (svc *service) Publish(event Event) {
-	if svc.events == nil {
-		panic("events not initialized")
+	batchID := nextID()
+	ll = make([]Lock, 0, len(cc))
+	for _, c := range cc {
+		c.batchID = batchID
+
+		var ref uint64
+		ref, err = svc.queueManager.queueLock(ctx, c)
+		l := Lock{
+			ID:        ref,
+			UserID:    c.UserID,
+			Resource:  c.Resource,
+			Operation: c.Operation,
+			State:     lockStateQueued,
+			BatchID:   batchID,
+			Source:    c.Source,
+			Owner:     ownerOrDefault(c.Owner),
+		}
+		if err != nil {
+			l.State = lockStateFailed
+		}
+		ll = append(ll, l)
 	}
 
-	svc.events.Publish(event)
+	return
 }
 
-// probeResource returns all of the locks on the given resource
+// UnlockMany releases (or unqueues) every constraint in cc
 //
-// The function returns both already acquired and queued locks
-func (svc *service) probeResource(ctx context.Context, r string) (tt []Lock, err error) {
-	bits := strings.Split(r, "/")
-	schema := bits[0]
-	path := bits[1:]
-
-	seen := make(map[string]struct{}, len(path))
-
-	var bb []byte
-	var auxOut []Lock
+// It's the counterpart of LockMany; releasing the whole group through a
+// single call keeps behavior obvious, though releasing (or losing) any one
+// member acquired via LockMany already cascades to the rest via Unlock.
+func (svc *service) UnlockMany(ctx context.Context, cc []Constraint) (err error) {
+	for _, c := range cc {
+		uErr := svc.Unlock(ctx, c)
+		if uErr != nil && err == nil {
+			err = uErr
+		}
+	}
 
-	// Check for all keys that are either as specific or less for the same resource.
-	// So if we're probing for a specific module, check all the wildcards corresponding to it.
+	return
+}
 
-	for i := len(path); i >= 0; i-- {
-		if i <= len(path)-1 {
-			path[i] = "*"
-		}
+// releaseBatchGroup releases every other acquired member of a LockMany batch
+// once one of them goes away, preserving the all-or-nothing guarantee on the
+// way out just as it's enforced on the way in
+func (svc *service) releaseBatchGroup(ctx context.Context, batchID uint64, except uint64) (err error) {
+	group := svc.batchGroups[batchID]
+	delete(svc.batchGroups, batchID)
 
-		r := fmt.Sprintf("%s/%s", schema, strings.Join(path, "/"))
-		if _, ok := seen[r]; ok {
+	for lockID, resource := range group {
+		if lockID == except {
 			continue
 		}
-		seen[r] = struct{}{}
 
-		// Get the currently stored locks
-		bb, err = svc.store.GetValue(ctx, r)
-		if err != nil && err.Error() == "not found" {
-			err = nil
+		rErr := svc.releaseLock(ctx, Constraint{Resource: resource}, lockID)
+		if rErr != nil && err == nil {
+			err = rErr
 			continue
 		}
-		if err != nil {
-			return
-		}
 
-		err = json.Unmarshal(bb, &auxOut)
-		tt = append(tt, auxOut...)
-		if err != nil {
-			return
+		svc.Publish(Event{
+			Kind: EbEventLockReleased,
+			Lock: Lock{ID: lockID, Resource: resource, BatchID: batchID, State: lockStateReleased},
+		})
+
+		dErr := svc.doQueued(ctx, Constraint{Resource: resource})
+		if dErr != nil && err == nil {
+			err = dErr
 		}
+	}
 
-		// Get queued locks
-		aux := svc.queueManager.queues[r]
-		if aux == nil {
+	return
+}
+
+// releaseQueuedBatch cancels every other still-queued member of a LockMany
+// batch once one of them is unlocked (or times out), so a caller never ends
+// up holding a partial batch that nobody asked for
+func (svc *service) releaseQueuedBatch(ctx context.Context, batchID uint64, except uint64) (err error) {
+	svc.queueManager.mux.Lock()
+	cc := append([]Constraint(nil), svc.queueManager.batches[batchID]...)
+	delete(svc.queueManager.batches, batchID)
+	svc.queueManager.mux.Unlock()
+
+	for _, qc := range cc {
+		if qc.id == except {
 			continue
 		}
 
-		for _, c := range aux.queue {
-			tt = append(tt, Lock{
-				ID:        c.id,
-				UserID:    c.UserID,
-				Resource:  c.Resource,
-				Operation: c.Operation,
-				State:     lockStateQueued,
-			})
+		rErr := svc.releaseQueued(ctx, qc, qc.id)
+		if rErr != nil && err == nil {
+			err = rErr
 		}
+
+		svc.Publish(Event{
+			Kind: EbEventLockResolved,
+			Lock: Lock{ID: qc.id, Resource: qc.Resource, Operation: qc.Operation, BatchID: batchID, State: lockStateFailed},
+		})
 	}
 
-	// @todo
 	return
 }
 
-// check returns the lock reference along with it's state
-func (svc *service) check(ctx context.Context, c Constraint) (lock Lock, state LockState, err error) {
-	aux, err := svc.probeResource(ctx, c.Resource)
-	if err != nil {
+// WaitLock acquires a lock, blocking until it's granted if it has to queue
+//
+// Lock's docstring already says a caller that gets queued has to wait for
+// the lock to resolve itself, but until now every caller had to roll that
+// poll loop against ProbeLock by hand. WaitLock is the missing "caller side"
+// of the existing queueing mechanism: it calls Lock once, and if the result
+// is queued, it subscribes to the event bus and blocks until the lock
+// resolves, fails, the context is cancelled, or c.Await elapses.
+func (svc *service) WaitLock(ctx context.Context, c Constraint) (l Lock, err error) {
+	l, err = svc.Lock(ctx, c)
+	if err != nil || l.State != lockStateQueued {
 		return
 	}
 
-	for _, t := range aux {
-		if !t.matchesConstraints(c) {
-			continue
+	ref := l.ID
+
+	// Buffered so a slow caller can't stall the Publish fan-out that every
+	// other subscriber (including other waiters) relies on
+	resolved := make(chan Lock, 8)
+	subID := svc.Subscribe(func(evt Event) {
+		if evt.Lock.ID != ref {
+			return
 		}
 
-		return t, t.State, nil
+		select {
+		case resolved <- evt.Lock:
+		default:
+		}
+	})
+	defer svc.Unsubscribe(subID)
+
+	// The lock may have already resolved in the gap between Lock returning
+	// and this Subscribe taking effect (e.g. another goroutine's Unlock
+	// promoted it right then, via doQueued/Publish); re-probe now that we're
+	// listening so that race doesn't turn into a lost wakeup that blocks
+	// this caller forever instead of surfacing the resolution it missed.
+	switch state, pErr := svc.ProbeLock(ctx, c, ref); {
+	case pErr != nil:
+		return l, pErr
+	case state == lockStateLocked:
+		l.State = lockStateLocked
+		return l, nil
+	case state == lockStateFailed:
+		return l, fmt.Errorf("gatekeep: lock %d failed to acquire", ref)
 	}
 
-	return lock, lockStateNil, nil
-}
-
-func (svc *service) cleanupStore(ctx context.Context) (err error) {
-	svc.mux.Lock()
-	defer svc.mux.Unlock()
+	var timeout <-chan time.Time
+	if c.Await > 0 {
+		tmr := time.NewTimer(c.Await)
+		defer tmr.Stop()
+		timeout = tmr.C
+	}
 
-	svc.logger.Debug("cleaning up stale locks")
-	defer svc.logger.Debug("cleaned up stale locks")
+	for {
+		select {
+		case lk := <-resolved:
+			switch lk.State {
+			case lockStateLocked:
+				return lk, nil
+			case lockStateFailed:
+				return lk, fmt.Errorf("gatekeep: lock %d failed to acquire", ref)
+			}
 
-	// @todo...
+		case <-timeout:
+			_ = svc.Unlock(ctx, c)
+			return l, fmt.Errorf("gatekeep: timed out waiting for lock %d", ref)
 
-	return
+		case <-ctx.Done():
+			_ = svc.Unlock(ctx, c)
+			return l, ctx.Err()
+		}
+	}
 }
 
-func (svc *service) cleanupQueues(ctx context.Context) (err error) {
+// Refresh extends the lease on an already-acquired lock
+//
+// It's the caller's responsibility to call this well before LeaseExpiresAt
+// while it's still alive and holding the lock; once the lease lapses
+// cleanupStore will consider the lock abandoned and release it
+func (svc *service) Refresh(ctx context.Context, lockID uint64, owner uint64) (err error) {
 	svc.mux.Lock()
 	defer svc.mux.Unlock()
 
-	svc.logger.Debug("cleaning up stale queues")
-	defer svc.logger.Debug("cleaned up stale queues")
-
-	qm := svc.queueManager
-	if qm == nil {
-		return
+	resource, ok := svc.locks[lockID]
+	if !ok {
+		resource, ok, err = svc.findLockResource(ctx, lockID)
+		if err != nil {
+			return err
+		}
+	}
+	if !ok {
+		return fmt.Errorf("gatekeep: lock %d not found", lockID)
 	}
 
-	qm.mux.Lock()
-	defer qm.mux.Unlock()
-
-	// Go backwards and spice out the ones that need to be removed.
-	// Broadcast down the buss so we can kill off the watchers.
-	now := time.Now()
-	for _, qq := range qm.queues {
-		for i := len(qq.queue) - 1; i >= 0; i-- {
-			c := qq.queue[i]
-			l := Lock{
-				ID:        c.id,
-				UserID:    c.UserID,
-				CreatedAt: c.queuedAt,
-				Resource:  c.Resource,
-				Operation: c.Operation,
+	var notOwner error
+	_, err = svc.casUpdate(ctx, resource, func(tt []Lock) ([]Lock, error) {
+		for i, t := range tt {
+			if t.ID != lockID {
+				continue
+			}
 
-				State: lockStateFailed,
+			if t.UserID != owner {
+				notOwner = fmt.Errorf("gatekeep: lock %d not owned by %d", lockID, owner)
+				return tt, nil
 			}
 
-			if !c.queuedAt.IsZero() && now.Before(c.queuedAt.Add(c.Await)) {
-				continue
+			if t.LeaseTTL > 0 {
+				tt[i].LeaseExpiresAt = time.Now().Add(t.LeaseTTL)
 			}
 
-			// Splice it out and publish the event
-			qq.queue = append(qq.queue[:i], qq.queue[i+1:]...)
-			svc.Publish(Event{
-				Kind: EbEventLockResolved,
-				Lock: l,
-			})
+			return tt, nil
 		}
+
+		notOwner = fmt.Errorf("gatekeep: lock %d not found", lockID)
+		return tt, nil
+	})
+	if err != nil {
+		return
 	}
 
-	return
+	return notOwner
 }
 
-func (svc *service) Watch(ctx context.Context) {
-	tcrGcQueued := time.NewTicker(time.Second * 5)
+// findLockResource locates which resource key lockID currently lives under
+// by scanning the store directly
+//
+// It's the fallback for when svc.locks doesn't know about a lock: svc.locks
+// only indexes what this process itself acquired via acquireLock, so it's
+// empty right after a restart and never covers a lock acquired by another
+// node against a shared DistributedStore. Callers must already hold svc.mux.
+func (svc *service) findLockResource(ctx context.Context, lockID uint64) (resource string, ok bool, err error) {
+	it, supportsIterate := svc.store.(Iterator)
+	if !supportsIterate {
+		return "", false, nil
+	}
 
-	// The store ticker is for a greater interval since it's a more hardcore operation
-	// @todo potentially keep some in memory index of what's to expire?
-	tcrGcStore := time.NewTicker(time.Minute * 5)
+	err = it.Iterate(ctx, "", func(key string, v []byte) error {
+		if ok {
+			return nil
+		}
 
-	svc.logger.Debug("watcher starting")
+		var tt []Lock
+		if uErr := json.Unmarshal(v, &tt); uErr != nil {
+			return uErr
+		}
 
-	var err error
-	go func() {
+		for _, t := range tt {
+			if t.ID == lockID {
+				resource = key
+				ok = true
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// ProbeLock returns the current state of the lock
+func (svc *service) ProbeLock(ctx context.Context, c Constraint, ref uint64) (state LockState, err error) {
+	svc.mux.Lock()
+	defer svc.mux.Unlock()
+
+	tt, err := svc.probeResource(ctx, c.Resource)
+	if err != nil {
+		return
+	}
+
+	for _, t := range tt {
+		if t.ID == ref {
+			return t.State, nil
+		}
+	}
+
+	return
+}
+
+func (svc *service) ProbeResource(ctx context.Context, r string) (tt []Lock, err error) {
+	svc.mux.RLock()
+	defer svc.mux.RUnlock()
+
+	return svc.probeResource(ctx, r)
+}
+
+// TopLocks returns the most contended locks across the whole store for
+// forensics purposes (e.g. an operator tracking down a leaked/zombie lock)
+//
+// It scans every resource key via an Iterator rather than relying on
+// svc.locks, since that index only covers locks acquired by this node; a
+// DistributedStore may hold locks acquired by other nodes too. The scan
+// deliberately doesn't hold svc.mux: against a DistributedStore, Iterate is a
+// blocking network call over the whole keyspace, and svc.mux is the same
+// mutex Lock/Unlock/LockMany need to acquire or release anything, so holding
+// it here would stall the whole service for as long as the scan takes. The
+// in-memory queued constraints are still read under queueManager.mux, just
+// only for as long as it takes to copy them out. Results are sorted
+// oldest-first so the most contended (longest-held/longest-queued) locks
+// surface first.
+//
+// @todo wire this up through the admin HTTP surface once one exists in this
+// tree (pkg/api/rest); there's nothing to attach it to yet in this snapshot
+func (svc *service) TopLocks(ctx context.Context, opts TopLocksOptions) (tt []Lock, err error) {
+	now := time.Now()
+
+	if it, ok := svc.store.(Iterator); ok {
+		err = it.Iterate(ctx, opts.ResourcePrefix, func(key string, v []byte) error {
+			var ll []Lock
+			if uErr := json.Unmarshal(v, &ll); uErr != nil {
+				return uErr
+			}
+
+			for _, l := range ll {
+				if !l.LeaseExpiresAt.IsZero() && now.After(l.LeaseExpiresAt) {
+					if !opts.IncludeStale {
+						continue
+					}
+					l.Stale = true
+				}
+
+				tt = append(tt, l)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	if opts.IncludeQueued {
+		svc.queueManager.mux.Lock()
+		for r, qq := range svc.queueManager.queues {
+			if opts.ResourcePrefix != "" && !strings.HasPrefix(r, opts.ResourcePrefix) {
+				continue
+			}
+
+			for _, c := range qq.queue {
+				tt = append(tt, Lock{
+					ID:        c.id,
+					UserID:    c.UserID,
+					CreatedAt: c.queuedAt,
+					Resource:  c.Resource,
+					Operation: c.Operation,
+					State:     lockStateQueued,
+					BatchID:   c.batchID,
+					Source:    c.Source,
+					Owner:     ownerOrDefault(c.Owner),
+				})
+			}
+		}
+		svc.queueManager.mux.Unlock()
+	}
+
+	sort.Slice(tt, func(i, j int) bool {
+		return lockAge(tt[i]).Before(lockAge(tt[j]))
+	})
+
+	if opts.Count > 0 && len(tt) > opts.Count {
+		tt = tt[:opts.Count]
+	}
+
+	return
+}
+
+func (svc *service) Subscribe(listener EventListener) int {
+	if svc.events == nil {
+		panic("events not initialized")
+	}
+
+	return svc.events.Subscribe(listener)
+}
+
+func (svc *service) Unsubscribe(id int) {
+	if svc.events == nil {
+		panic("events not initialized")
+	}
+
+	svc.events.Unsubscribe(id)
+}
+
+func (svc *service) Publish(event Event) {
+	if svc.events == nil {
+		panic("events not initialized")
+	}
+
+	svc.events.Publish(event)
+}
+
+// bridgeDistributedEvents relays a DistributedStore's Watch stream onto the
+// in-process event bus, so a subscriber on this node sees the locks another
+// node writes to the same backend. Only locks still listed under a key are
+// relayed (see relayStoreEvent); a release by another node is not published
+// as EbEventLockReleased. It's a no-op against a plain (non-distributed) store.
+func (svc *service) bridgeDistributedEvents(ctx context.Context) (err error) {
+	ds, ok := svc.store.(DistributedStore)
+	if !ok {
+		return nil
+	}
+
+	ch, err := ds.Watch(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case evt, open := <-ch:
+				if !open {
+					return
+				}
+
+				svc.relayStoreEvent(evt)
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// relayStoreEvent decodes a raw store-level change back into the locks it
+// affects and republishes each of them on the event bus
+//
+// Only the locks present in evt.Value are published, as EbEventLockResolved
+// for a put. A lock dropped from a key's list (which is how releaseLock
+// releases one) is not reported, and delete events, which carry no value, are
+// not relayed at all.
+func (svc *service) relayStoreEvent(evt StoreEvent) {
+	kind := EbEventLockResolved
+	if evt.Type == StoreEventDelete {
+		kind = EbEventLockReleased
+	}
+
+	if len(evt.Value) == 0 {
+		return
+	}
+
+	tt := make([]Lock, 0)
+	if err := json.Unmarshal(evt.Value, &tt); err != nil {
+		svc.logger.Error("failed to decode distributed store event", zap.Error(err))
+		return
+	}
+
+	for _, l := range tt {
+		svc.Publish(Event{Kind: kind, Lock: l})
+	}
+}
+
+// probeResource returns all of the locks on the given resource
+//
+// The function returns both already acquired and queued locks
+func (svc *service) probeResource(ctx context.Context, r string) (tt []Lock, err error) {
+	bits := strings.Split(r, "/")
+	schema := bits[0]
+	path := bits[1:]
+
+	seen := make(map[string]struct{}, len(path))
+
+	var bb []byte
+	var auxOut []Lock
+
+	// Check for all keys that are either as specific or less for the same resource.
+	// So if we're probing for a specific module, check all the wildcards corresponding to it.
+
+	for i := len(path); i >= 0; i-- {
+		if i <= len(path)-1 {
+			path[i] = "*"
+		}
+
+		r := fmt.Sprintf("%s/%s", schema, strings.Join(path, "/"))
+		if _, ok := seen[r]; ok {
+			continue
+		}
+		seen[r] = struct{}{}
+
+		// Get the currently stored locks
+		bb, err = svc.store.GetValue(ctx, r)
+		if err != nil && err.Error() == "not found" {
+			err = nil
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		err = json.Unmarshal(bb, &auxOut)
+		tt = append(tt, auxOut...)
+		if err != nil {
+			return
+		}
+
+		// Get queued locks
+		aux := svc.queueManager.queues[r]
+		if aux == nil {
+			continue
+		}
+
+		for _, c := range aux.queue {
+			tt = append(tt, Lock{
+				ID:        c.id,
+				UserID:    c.UserID,
+				Resource:  c.Resource,
+				Operation: c.Operation,
+				State:     lockStateQueued,
+				BatchID:   c.batchID,
+				Source:    c.Source,
+				Owner:     ownerOrDefault(c.Owner),
+			})
+		}
+	}
+
+	// @todo
+	return
+}
+
+// check returns the lock reference along with it's state
+func (svc *service) check(ctx context.Context, c Constraint) (lock Lock, state LockState, err error) {
+	aux, err := svc.probeResource(ctx, c.Resource)
+	if err != nil {
+		return
+	}
+
+	for _, t := range aux {
+		if !t.matchesConstraints(c) {
+			continue
+		}
+
+		return t, t.State, nil
+	}
+
+	return lock, lockStateNil, nil
+}
+
+// canonicalSortConstraints orders constraints by resource and then operation
+//
+// Two callers locking overlapping resource sets in different orders will
+// still attempt acquisition in the same global order, which is what removes
+// the classic ordering deadlock between e.g. a {A,B,C} and a {C,B,A} caller
+func canonicalSortConstraints(cc []Constraint) {
+	sort.Slice(cc, func(i, j int) bool {
+		if cc[i].Resource != cc[j].Resource {
+			return cc[i].Resource < cc[j].Resource
+		}
+
+		return cc[i].Operation < cc[j].Operation
+	})
+}
+
+// probeAllAcquirable reports whether every constraint in cc could be granted
+// right now, as if acquired all together; it doesn't mutate any state
+func (svc *service) probeAllAcquirable(ctx context.Context, cc []Constraint) (ok bool, err error) {
+	for _, c := range cc {
+		var ll []Lock
+		ll, err = svc.probeResource(ctx, c.Resource)
+		if err != nil {
+			return
+		}
+
+		already := false
+		for _, l := range ll {
+			if l.matchesConstraints(c) {
+				already = true
+				break
+			}
+		}
+		if already {
+			continue
+		}
+
+		allRead := c.Operation == OpRead
+		for _, t := range ll {
+			allRead = allRead && t.Operation == OpRead
+		}
+
+		if len(ll) > 0 && !allRead {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// maxCasAttempts bounds how many times casUpdate reloads and retries after
+// losing a CAS race to another node writing the same key, so a resource
+// under constant contention fails a single acquisition instead of spinning
+// forever with svc.mux held (casUpdate is always called while it is).
+const maxCasAttempts = 32
+
+// errQuorumNotMet is returned by casUpdate when the primary store accepted a
+// CAS write but it wasn't confirmed by enough of svc.replicas. The write has
+// already landed at that point, so unlike a lost CAS race this is terminal:
+// casUpdate must not loop back and run mutate again, which would re-apply it
+// on top of its own still-unconfirmed write (e.g. appending a duplicate Lock).
+var errQuorumNotMet = fmt.Errorf("gatekeep: quorum not met")
+
+// casUpdate applies mutate to the Lock list currently stored at key and
+// persists the result
+//
+// When store is a DistributedStore the read-modify-write is done as a
+// CompareAndSwap loop (optionally confirmed against a Quorum of replicas) so
+// two nodes racing to update the same resource can't clobber each other;
+// against a plain store it falls back to the original blind
+// GetValue+SetValue.
+func (svc *service) casUpdate(ctx context.Context, key string, mutate func(tt []Lock) ([]Lock, error)) (tt []Lock, err error) {
+	ds, distributed := svc.store.(DistributedStore)
+
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		if attempt >= maxCasAttempts {
+			return nil, fmt.Errorf("gatekeep: giving up on %q after %d CAS attempts", key, maxCasAttempts)
+		}
+
+		var baseB []byte
+		baseB, err = svc.store.GetValue(ctx, key)
+		if err != nil && err.Error() != "not found" {
+			return
+		}
+		err = nil
+
+		cur := make([]Lock, 0)
+		if len(baseB) > 0 {
+			err = json.Unmarshal(baseB, &cur)
+			if err != nil {
+				return
+			}
+		}
+
+		tt, err = mutate(cur)
+		if err != nil {
+			return
+		}
+
+		var bb []byte
+		bb, err = json.Marshal(tt)
+		if err != nil {
+			return
+		}
+
+		if !distributed {
+			err = svc.store.SetValue(ctx, key, bb)
+			return
+		}
+
+		var written bool
+		written, err = svc.casWithQuorum(ctx, ds, key, baseB, bb)
+		if err != nil {
+			// Either a real store error, or errQuorumNotMet after a primary
+			// write that did land: both are terminal, not a reason to retry.
+			return
+		}
+		if written {
+			return
+		}
+
+		// lost the race to another node updating the same key; reload and retry
+	}
+}
+
+// casWithQuorum performs the CompareAndSwap against the primary store and,
+// when the service is configured with a Quorum greater than 1, against every
+// store in svc.replicas too, confirming the write only once that many of
+// them (primary included) actually accepted it. This mirrors the dsync-style
+// quorum model where a write only counts once a majority of independent
+// replicas agree on it; without any configured replicas there's only ever
+// the primary to ask, so Quorum beyond 1 is clamped down to that at New.
+//
+// written reports whether the primary accepted the CAS at all: false means
+// casUpdate lost the race to another node and should reload and retry;
+// true with a non-nil errQuorumNotMet means the primary write landed but
+// wasn't confirmed by enough replicas, which callers must treat as terminal
+// rather than retriable, since the store already reflects the write.
+func (svc *service) casWithQuorum(ctx context.Context, ds DistributedStore, key string, expect, new []byte) (written bool, err error) {
+	ok, err := ds.CompareAndSwap(ctx, key, expect, new)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+
+	if svc.quorum <= 1 {
+		return true, nil
+	}
+
+	confirmed := 1
+	for _, r := range svc.replicas {
+		rOk, rErr := r.CompareAndSwap(ctx, key, expect, new)
+		if rErr != nil {
+			continue
+		}
+
+		if rOk {
+			confirmed++
+		}
+	}
+
+	if confirmed < svc.quorum {
+		return true, errQuorumNotMet
+	}
+
+	return true, nil
+}
+
+// cleanupStore drops locks whose lease has expired without being refreshed
+//
+// This is how we recover from a caller that acquired a lock and then
+// crashed (or otherwise went away) before calling Unlock: once its
+// LeaseExpiresAt is in the past we consider the lock abandoned, release it
+// and let the next queued waiter (if any) get promoted.
+func (svc *service) cleanupStore(ctx context.Context) (err error) {
+	svc.mux.Lock()
+	defer svc.mux.Unlock()
+
+	svc.logger.Debug("cleaning up stale locks")
+	defer svc.logger.Debug("cleaned up stale locks")
+
+	now := time.Now()
+
+	// Resources currently holding at least one acquired lock. svc.locks only
+	// covers locks this node itself acquired, so it misses anything a
+	// DistributedStore is holding for another node (or for this node before a
+	// restart emptied the map); scan the store directly too, the same way
+	// TopLocks does, whenever it supports it.
+	resources := make(map[string]struct{})
+	for _, r := range svc.locks {
+		resources[r] = struct{}{}
+	}
+
+	if it, ok := svc.store.(Iterator); ok {
+		iErr := it.Iterate(ctx, "", func(key string, v []byte) error {
+			resources[key] = struct{}{}
+			return nil
+		})
+		if iErr != nil {
+			return iErr
+		}
+	}
+
+	for r := range resources {
+		// casUpdate may call mutate more than once if a racing node wins a CAS
+		// attempt against the same key first; expired is reset on every call
+		// so logging/publishing below only ever reflects the one invocation
+		// whose kept list actually got persisted, not however many times we
+		// had to retry to get there.
+		var expired []Lock
+
+		_, err = svc.casUpdate(ctx, r, func(tt []Lock) ([]Lock, error) {
+			kept := make([]Lock, 0, len(tt))
+			expired = expired[:0]
+
+			for _, t := range tt {
+				if t.LeaseExpiresAt.IsZero() || now.Before(t.LeaseExpiresAt) {
+					kept = append(kept, t)
+					continue
+				}
+
+				t.State = lockStateReleased
+				expired = append(expired, t)
+			}
+
+			return kept, nil
+		})
+		if err != nil && err.Error() == "not found" {
+			err = nil
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		if len(expired) == 0 {
+			continue
+		}
+
+		for _, t := range expired {
+			svc.logger.Warn("releasing expired lock",
+				zap.Uint64("lockID", t.ID),
+				zap.String("resource", t.Resource))
+
+			delete(svc.locks, t.ID)
+
+			svc.Publish(Event{
+				Kind: EbEventLockReleased,
+				Lock: t,
+			})
+
+			if t.BatchID > 0 {
+				if bErr := svc.releaseBatchGroup(ctx, t.BatchID, t.ID); bErr != nil {
+					svc.logger.Error("failed to release expired lock's batch group",
+						zap.Uint64("batchID", t.BatchID), zap.Error(bErr))
+				}
+			}
+		}
+
+		err = svc.doQueued(ctx, Constraint{Resource: r})
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (svc *service) cleanupQueues(ctx context.Context) (err error) {
+	svc.mux.Lock()
+	defer svc.mux.Unlock()
+
+	svc.logger.Debug("cleaning up stale queues")
+	defer svc.logger.Debug("cleaned up stale queues")
+
+	qm := svc.queueManager
+	if qm == nil {
+		return
+	}
+
+	qm.mux.Lock()
+	defer qm.mux.Unlock()
+
+	// Go backwards and spice out the ones that need to be removed.
+	// Broadcast down the buss so we can kill off the watchers.
+	now := time.Now()
+	for _, qq := range qm.queues {
+		for i := len(qq.queue) - 1; i >= 0; i-- {
+			c := qq.queue[i]
+			l := Lock{
+				ID:        c.id,
+				UserID:    c.UserID,
+				CreatedAt: c.queuedAt,
+				Resource:  c.Resource,
+				Operation: c.Operation,
+
+				State: lockStateFailed,
+			}
+
+			if !c.queuedAt.IsZero() && now.Before(c.queuedAt.Add(c.Await)) {
+				continue
+			}
+
+			// Splice it out and publish the event
+			qq.queue = append(qq.queue[:i], qq.queue[i+1:]...)
+			svc.Publish(Event{
+				Kind: EbEventLockResolved,
+				Lock: l,
+			})
+		}
+	}
+
+	return
+}
+
+// storeGcInterval returns how often Watch should tick cleanupStore, a
+// fraction of the smallest LeaseTTL acquired so far so a zombie lock is
+// caught well before anyone would notice it's gone stale
+func (svc *service) storeGcInterval() time.Duration {
+	svc.mux.RLock()
+	defer svc.mux.RUnlock()
+
+	if svc.minLeaseTTL == 0 {
+		return defaultStoreGcInterval
+	}
+
+	i := svc.minLeaseTTL / leaseCheckFraction
+	if i <= 0 {
+		return time.Second
+	}
+
+	return i
+}
+
+func (svc *service) Watch(ctx context.Context) {
+	tcrGcQueued := time.NewTicker(time.Second * 5)
+
+	// The store ticker is for a greater interval since it's a more hardcore operation
+	// It's re-armed after every tick to a fraction of the smallest LeaseTTL seen so
+	// far so zombie locks don't linger for longer than their owners would expect;
+	// the queue ticker also re-arms it as soon as a shorter LeaseTTL shows up
+	storeInterval := defaultStoreGcInterval
+	tcrGcStore := time.NewTicker(storeInterval)
+
+	if err := svc.bridgeDistributedEvents(ctx); err != nil {
+		svc.logger.Error("failed to watch distributed store", zap.Error(err))
+	}
+
+	svc.logger.Debug("watcher starting")
+
+	var err error
+	go func() {
 		for {
 			select {
 			case <-tcrGcStore.C:
@@ -473,6 +1541,9 @@ func (svc *service) Watch(ctx context.Context) {
 					err = nil
 				}
 
+				storeInterval = svc.storeGcInterval()
+				tcrGcStore.Reset(storeInterval)
+
 			case <-tcrGcQueued.C:
 				svc.logger.Debug("tick cleanup queue")
 
@@ -483,6 +1554,11 @@ func (svc *service) Watch(ctx context.Context) {
 					err = nil
 				}
 
+				if i := svc.storeGcInterval(); i < storeInterval {
+					storeInterval = i
+					tcrGcStore.Reset(i)
+				}
+
 			case <-ctx.Done():
 				svc.logger.Debug("watcher stopping")
 				tcrGcQueued.Stop()
@@ -493,8 +1569,8 @@ func (svc *service) Watch(ctx context.Context) {
 	}()
 }
 
-// @todo we could consider prioritizing some/all read locks over write locks
-// so we can have a higher throughput
+// queueLock appends c to its resource's queue, keeping that queue's
+// writer-starvation counters (used by PolicyFairReaderPreferring) up to date
 func (qm *queueManager) queueLock(ctx context.Context, c Constraint) (ref uint64, err error) {
 	qm.mux.Lock()
 	defer qm.mux.Unlock()
@@ -511,12 +1587,72 @@ func (qm *queueManager) queueLock(ctx context.Context, c Constraint) (ref uint64
 	q := qm.queues[key]
 	c.id = nextID()
 	c.queuedAt = time.Now()
+
+	wasEmpty := len(q.queue) == 0
+	headIsWriter := !wasEmpty && q.queue[0].Operation == OpWrite
+
 	q.queue = append(q.queue, c)
 	qm.queues[key] = q
 
+	switch {
+	case wasEmpty:
+		q.refreshHead()
+	case headIsWriter && c.Operation == OpRead:
+		q.readersSinceWriterHead++
+	}
+
+	if c.batchID > 0 {
+		if qm.batches == nil {
+			qm.batches = make(map[uint64][]Constraint)
+		}
+		qm.batches[c.batchID] = append(qm.batches[c.batchID], c)
+	}
+
 	return c.id, nil
 }
 
+// refreshHead resets this queue's writer-starvation counters to reflect
+// whatever constraint now sits at its head, e.g. after a dequeue
+func (q *queue) refreshHead() {
+	q.readersSinceWriterHead = 0
+	q.writerHeadSince = time.Time{}
+
+	if len(q.queue) > 0 && q.queue[0].Operation == OpWrite {
+		q.writerHeadSince = time.Now()
+	}
+}
+
+// dequeueLocked removes a single queued constraint by id from its resource
+// queue; callers must already hold qm.mux
+func (qm *queueManager) dequeueLocked(resource string, id uint64) {
+	q := qm.queues[resource]
+	if q == nil {
+		return
+	}
+
+	var headRemoved bool
+	for i, qc := range q.queue {
+		if qc.id != id {
+			continue
+		}
+
+		headRemoved = i == 0
+		q.queue = append(q.queue[:i], q.queue[i+1:]...)
+		break
+	}
+
+	if len(q.queue) == 0 {
+		delete(qm.queues, resource)
+		return
+	}
+
+	if headRemoved {
+		q.refreshHead()
+	}
+}
+
+// doQueued promotes whatever queued constraints are now acquirable for
+// c.Resource, picking the promotion set according to svc.policy
 func (svc *service) doQueued(ctx context.Context, c Constraint) (err error) {
 	svc.queueManager.mux.Lock()
 	defer svc.queueManager.mux.Unlock()
@@ -531,35 +1667,64 @@ func (svc *service) doQueued(ctx context.Context, c Constraint) (err error) {
 		return
 	}
 
-	doReads := q.queue[0].Operation == OpRead
+	if q.queue[0].batchID > 0 {
+		return svc.promoteBatchLocked(ctx, q.queue[0].batchID)
+	}
+
+	headBefore := q.queue[0].id
+
+	switch svc.policy {
+	case PolicyReaderPreferring:
+		err = svc.promoteReadersLocked(ctx, q, false)
+	case PolicyFairReaderPreferring:
+		err = svc.promoteReadersLocked(ctx, q, true)
+	default:
+		err = svc.promoteFIFOLocked(ctx, q)
+	}
+	if err != nil {
+		return
+	}
+
+	if len(q.queue) == 0 {
+		delete(svc.queueManager.queues, c.Resource)
+		return
+	}
+
+	// Only re-stamp the head constraint's starvation counters when the head
+	// actually changed: doQueued re-enters on every Unlock for this resource,
+	// including releases that promote nothing, and resetting readersSinceWriterHead/
+	// writerHeadSince on those no-op re-entries would let a sustained stream of
+	// unrelated reader releases keep erasing a queued writer's starvation clock.
+	if q.queue[0].id != headBefore {
+		q.refreshHead()
+	}
+	return
+}
 
-	if !doReads {
-		// Check if we can acquire a new one
+// promoteFIFOLocked is PolicyFIFO: promote a single writer at the head, or
+// the contiguous run of readers starting at the head; callers must already
+// hold queueManager.mux
+func (svc *service) promoteFIFOLocked(ctx context.Context, q *queue) (err error) {
+	if q.queue[0].Operation != OpRead {
 		qc := q.queue[0]
 
-		// Probe existing resource locks so we can figure out what we can do
-		var tt []Lock
-		tt, err = svc.probeResource(ctx, qc.Resource)
+		var acquired []Lock
+		acquired, err = svc.acquiredLocks(ctx, qc.Resource)
 		if err != nil {
 			return
 		}
 
-		// Check if we already have this lock so we can potentially extend the lock
-		for _, t := range tt {
-			if t.ID == qc.id {
-				continue
-			}
-
-			// If there are any locks and we're trying a write lock; no bueno
+		// Any other acquired lock blocks a write; no bueno
+		if len(acquired) > 0 {
 			return
 		}
 
 		q.queue = q.queue[1:]
 
-		// @todo
 		_, err = svc.acquireLock(ctx, qc, qc.id)
 		if err != nil {
 			svc.logger.Error("queued failed to acquire lock", zap.Error(err))
+			err = nil
 		}
 
 		return
@@ -584,23 +1749,117 @@ func (svc *service) doQueued(ctx context.Context, c Constraint) (err error) {
 	return
 }
 
-func (svc *service) acquireLock(ctx context.Context, c Constraint, ids ...uint64) (l Lock, err error) {
-	tt := make([]Lock, 0)
+// promoteReadersLocked implements PolicyReaderPreferring (and, when fair is
+// true, PolicyFairReaderPreferring): every queued reader is promoted
+// regardless of queue position as long as there's no active writer, instead
+// of only the contiguous prefix PolicyFIFO promotes. When fair is true and
+// the writer at the head has been skipped by more than svc.maxReaderBurst
+// readers, or has waited longer than svc.maxWriterWait, no more readers are
+// promoted until that writer runs; callers must already hold queueManager.mux
+func (svc *service) promoteReadersLocked(ctx context.Context, q *queue, fair bool) (err error) {
+	if fair && svc.writerStarved(q) {
+		return svc.promoteFIFOLocked(ctx, q)
+	}
 
-	// Get current locks from the store
-	// @todo we can probably pass the OG slice around
-	baseB, err := svc.store.GetValue(ctx, c.Resource)
-	if err != nil && err.Error() != "not found" {
+	acquired, err := svc.acquiredLocks(ctx, q.queue[0].Resource)
+	if err != nil {
 		return
 	}
 
-	if len(baseB) > 0 {
-		err = json.Unmarshal(baseB, &tt)
-		if err != nil {
+	// A write lock is already held; nothing is acquirable until it's released
+	for _, t := range acquired {
+		if t.Operation == OpWrite {
 			return
 		}
 	}
 
+	kept := q.queue[:0]
+	for _, qc := range q.queue {
+		if qc.Operation != OpRead {
+			kept = append(kept, qc)
+			continue
+		}
+
+		_, lErr := svc.acquireLock(ctx, qc, qc.id)
+		if lErr != nil {
+			svc.logger.Error("queued failed to acquire lock", zap.Error(lErr))
+			kept = append(kept, qc)
+		}
+	}
+
+	q.queue = kept
+	return
+}
+
+// writerStarved reports whether the writer at q's head has been skipped by
+// more readers than svc.maxReaderBurst allows, or has waited longer than
+// svc.maxWriterWait, and so must run before any more readers are promoted
+func (svc *service) writerStarved(q *queue) bool {
+	if q.queue[0].Operation != OpWrite {
+		return false
+	}
+
+	if svc.maxReaderBurst > 0 && q.readersSinceWriterHead > svc.maxReaderBurst {
+		return true
+	}
+
+	if svc.maxWriterWait > 0 && !q.writerHeadSince.IsZero() && time.Since(q.writerHeadSince) > svc.maxWriterWait {
+		return true
+	}
+
+	return false
+}
+
+// acquiredLocks returns the locks currently held (not queued) for resource
+func (svc *service) acquiredLocks(ctx context.Context, resource string) (tt []Lock, err error) {
+	bb, err := svc.store.GetValue(ctx, resource)
+	if err != nil && err.Error() == "not found" {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(bb, &tt)
+	return
+}
+
+// promoteBatchLocked tries to acquire every member of a LockMany batch at
+// once; callers must already hold queueManager.mux. A batch only ever moves
+// out of lockStateQueued when all of its constraints are simultaneously
+// satisfiable, so a partially-acquirable batch is left queued, untouched.
+func (svc *service) promoteBatchLocked(ctx context.Context, batchID uint64) (err error) {
+	cc := svc.queueManager.batches[batchID]
+	if len(cc) == 0 {
+		delete(svc.queueManager.batches, batchID)
+		return
+	}
+
+	var acquirable bool
+	acquirable, err = svc.probeAllAcquirable(ctx, cc)
+	if err != nil {
+		return
+	}
+
+	if !acquirable {
+		return
+	}
+
+	for _, qc := range cc {
+		svc.queueManager.dequeueLocked(qc.Resource, qc.id)
+
+		_, err = svc.acquireLock(ctx, qc, qc.id)
+		if err != nil {
+			svc.logger.Error("queued batch failed to acquire lock", zap.Error(err))
+			err = nil
+		}
+	}
+
+	delete(svc.queueManager.batches, batchID)
+	return
+}
+
+func (svc *service) acquireLock(ctx context.Context, c Constraint, ids ...uint64) (l Lock, err error) {
 	id := nextID()
 	if len(ids) > 0 {
 		id = ids[0]
@@ -615,19 +1874,54 @@ func (svc *service) acquireLock(ctx context.Context, c Constraint, ids ...uint64
 		State:     lockStateLocked,
 
 		AcquiredAt: time.Now(),
+
+		LeaseTTL: c.LeaseTTL,
+
+		Source: c.Source,
+		Owner:  ownerOrDefault(c.Owner),
 	}
-	tt = append(tt, l)
 
-	bb, err := json.Marshal(tt)
-	if err != nil {
-		return
+	if c.LeaseTTL > 0 {
+		l.LeaseExpiresAt = l.AcquiredAt.Add(c.LeaseTTL)
+
+		if svc.minLeaseTTL == 0 || c.LeaseTTL < svc.minLeaseTTL {
+			svc.minLeaseTTL = c.LeaseTTL
+		}
+	}
+
+	if c.batchID > 0 {
+		l.BatchID = c.batchID
+
+		if svc.batchGroups == nil {
+			svc.batchGroups = make(map[uint64]map[uint64]string)
+		}
+		if svc.batchGroups[c.batchID] == nil {
+			svc.batchGroups[c.batchID] = make(map[uint64]string)
+		}
+		svc.batchGroups[c.batchID][l.ID] = c.Resource
 	}
 
-	err = svc.store.SetValue(ctx, c.Resource, bb)
+	_, err = svc.casUpdate(ctx, c.Resource, func(tt []Lock) ([]Lock, error) {
+		// tt is whatever's actually in the store right now, read as part of
+		// this same CAS attempt; it can hold a conflicting lock a different
+		// node wrote after c.Resource was probed (and svc.mux released) but
+		// before this CAS round-trip, so it has to be re-checked here rather
+		// than trusting that earlier probe.
+		if conflictsWithHeld(tt, c) {
+			return nil, fmt.Errorf("gatekeep: lock %d can no longer be acquired on %q", l.ID, c.Resource)
+		}
+
+		return append(tt, l), nil
+	})
 	if err != nil {
 		return
 	}
 
+	if svc.locks == nil {
+		svc.locks = make(map[uint64]string)
+	}
+	svc.locks[l.ID] = c.Resource
+
 	svc.Publish(Event{
 		Kind: EbEventLockResolved,
 		Lock: l,
@@ -638,33 +1932,23 @@ func (svc *service) acquireLock(ctx context.Context, c Constraint, ids ...uint64
 
 // releaseLock removes the lock from the store
 func (svc *service) releaseLock(ctx context.Context, c Constraint, ref uint64) (err error) {
-	baseB, err := svc.store.GetValue(ctx, c.Resource)
-	if err != nil && err.Error() != "not found" {
-		return
-	}
-
-	tt := make([]Lock, 0)
-	if len(baseB) > 0 {
-		err = json.Unmarshal(baseB, &tt)
-		if err != nil {
-			return
-		}
-	}
-
-	aux := make([]Lock, 0, len(tt))
-	for _, t := range tt {
-		if t.ID == ref {
-			continue
+	_, err = svc.casUpdate(ctx, c.Resource, func(tt []Lock) ([]Lock, error) {
+		aux := make([]Lock, 0, len(tt))
+		for _, t := range tt {
+			if t.ID == ref {
+				continue
+			}
+			aux = append(aux, t)
 		}
-		aux = append(aux, t)
-	}
-
-	bb, err := json.Marshal(aux)
+		return aux, nil
+	})
 	if err != nil {
 		return
 	}
 
-	return svc.store.SetValue(ctx, c.Resource, bb)
+	delete(svc.locks, ref)
+
+	return
 }
 
 // releaseQueued removes the lock from the queue
@@ -698,6 +1982,35 @@ func (svc *service) releaseQueued(ctx context.Context, c Constraint, ref uint64)
 	return
 }
 
+// lockAge returns the timestamp TopLocks sorts by: when the lock was
+// acquired, or when it was queued for locks still waiting
+func lockAge(l Lock) time.Time {
+	if !l.AcquiredAt.IsZero() {
+		return l.AcquiredAt
+	}
+
+	return l.CreatedAt
+}
+
+// conflictsWithHeld reports whether a c-shaped lock can no longer be acquired
+// given the locks actually held in tt: a write needs tt empty, a read only
+// needs tt free of other writes. Same read/many-writers-none rule as the
+// allRead check in Lock, just evaluated against a freshly-read tt instead of
+// an earlier, possibly stale probeResource snapshot.
+func conflictsWithHeld(tt []Lock, c Constraint) bool {
+	if c.Operation == OpWrite {
+		return len(tt) > 0
+	}
+
+	for _, t := range tt {
+		if t.Operation == OpWrite {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (t Lock) matchesConstraints(c Constraint) (ok bool) {
 	// Can't do anything
 	if t.UserID != c.UserID || t.Resource != c.Resource {
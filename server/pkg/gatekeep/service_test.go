@@ -0,0 +1,134 @@
+package gatekeep
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// memStore is a minimal in-memory store, just enough to exercise the
+// queueManager scheduling policies without a real backend
+type memStore struct {
+	mux sync.Mutex
+	kv  map[string][]byte
+}
+
+func newMemStore() *memStore {
+	return &memStore{kv: make(map[string][]byte)}
+}
+
+func (s *memStore) GetValue(ctx context.Context, key string) ([]byte, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	v, ok := s.kv[key]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+
+	return v, nil
+}
+
+func (s *memStore) SetValue(ctx context.Context, key string, v []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.kv[key] = v
+	return nil
+}
+
+func (s *memStore) DeleteValue(ctx context.Context, key string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.kv, key)
+	return nil
+}
+
+func (s *memStore) Iterate(ctx context.Context, prefix string, fn func(key string, v []byte) error) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for k, v := range s.kv {
+		if prefix != "" && !strings.HasPrefix(k, prefix) {
+			continue
+		}
+
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TestFairReaderPreferringBoundsWriterWait proves that a writer queued under
+// PolicyFairReaderPreferring eventually gets promoted once it's been skipped
+// by more readers than MaxReaderBurst allows, even under a sustained stream
+// of unrelated reader releases on the same resource re-entering doQueued in
+// the meantime (each of those used to reset the writer's starvation
+// counters back to zero).
+func TestFairReaderPreferringBoundsWriterWait(t *testing.T) {
+	svc, err := New(zap.NewNop(), newMemStore(), WithPolicy(PolicyFairReaderPreferring), WithMaxReaderBurst(2))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx := context.Background()
+	resource := "corteza::sys/module/1"
+
+	readerA := Constraint{Resource: resource, Operation: OpRead, UserID: 1, Await: time.Minute}
+	if l, lErr := svc.Lock(ctx, readerA); lErr != nil || l.State != lockStateLocked {
+		t.Fatalf("reader A: state=%v err=%v", l.State, lErr)
+	}
+
+	writer := Constraint{Resource: resource, Operation: OpWrite, UserID: 2, Await: time.Minute}
+	wl, err := svc.Lock(ctx, writer)
+	if err != nil || wl.State != lockStateQueued {
+		t.Fatalf("writer: state=%v err=%v", wl.State, err)
+	}
+
+	// noop is a constraint that never matches an acquired/queued lock; Unlock
+	// still re-enters doQueued for the resource via its deferred call, which
+	// is exactly how an unrelated release on a busy resource behaves
+	noop := Constraint{Resource: resource, Operation: OpRead, UserID: 9999}
+
+	var rr []Constraint
+	for i := 0; i < 5; i++ {
+		rc := Constraint{Resource: resource, Operation: OpRead, UserID: uint64(100 + i), Await: time.Minute}
+		rr = append(rr, rc)
+
+		l, lErr := svc.Lock(ctx, rc)
+		if lErr != nil || l.State != lockStateQueued {
+			t.Fatalf("reader %d: state=%v err=%v", i, l.State, lErr)
+		}
+
+		if uErr := svc.Unlock(ctx, noop); uErr != nil {
+			t.Fatalf("noop unlock %d: %v", i, uErr)
+		}
+	}
+
+	// release every read lock still standing between the writer and the
+	// resource being free
+	if uErr := svc.Unlock(ctx, readerA); uErr != nil {
+		t.Fatalf("unlock reader A: %v", uErr)
+	}
+	for i, rc := range rr {
+		if uErr := svc.Unlock(ctx, rc); uErr != nil {
+			t.Fatalf("unlock reader %d: %v", i, uErr)
+		}
+	}
+
+	state, err := svc.ProbeLock(ctx, writer, wl.ID)
+	if err != nil {
+		t.Fatalf("ProbeLock: %v", err)
+	}
+	if state != lockStateLocked {
+		t.Fatalf("writer still starved after exceeding MaxReaderBurst, state=%q", state)
+	}
+}
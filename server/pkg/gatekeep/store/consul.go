@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/cortezaproject/corteza/server/pkg/gatekeep"
+	consul "github.com/hashicorp/consul/api"
+)
+
+// Consul is a gatekeep.DistributedStore backed by a Consul KV store
+//
+// CompareAndSwap uses Consul's built-in check-and-set (keyed off
+// ModifyIndex), Watch polls Consul's blocking queries since the KV API has
+// no native push notifications, and Lease is implemented on top of Consul
+// sessions since the KV API itself has no concept of a per-key TTL.
+type Consul struct {
+	cl *consul.Client
+	kv *consul.KV
+}
+
+// NewConsul wraps an already-connected consul client as a gatekeep.DistributedStore
+func NewConsul(cl *consul.Client) *Consul {
+	return &Consul{cl: cl, kv: cl.KV()}
+}
+
+func (s *Consul) GetValue(ctx context.Context, key string) ([]byte, error) {
+	kv, _, err := s.kv.Get(key, (&consul.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if kv == nil {
+		return nil, errNotFound
+	}
+
+	return kv.Value, nil
+}
+
+func (s *Consul) SetValue(ctx context.Context, key string, v []byte) error {
+	_, err := s.kv.Put(&consul.KVPair{Key: key, Value: v}, (&consul.WriteOptions{}).WithContext(ctx))
+	return err
+}
+
+func (s *Consul) DeleteValue(ctx context.Context, key string) error {
+	_, err := s.kv.Delete(key, (&consul.WriteOptions{}).WithContext(ctx))
+	return err
+}
+
+// CompareAndSwap replaces expect with new for key using Consul's
+// check-and-set semantics keyed off the current ModifyIndex; an empty
+// expect means "key must not exist yet".
+func (s *Consul) CompareAndSwap(ctx context.Context, key string, expect, new []byte) (bool, error) {
+	cur, _, err := s.kv.Get(key, (&consul.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	var modifyIndex uint64
+	if cur != nil {
+		if string(cur.Value) != string(expect) {
+			return false, nil
+		}
+		modifyIndex = cur.ModifyIndex
+	} else if len(expect) != 0 {
+		return false, nil
+	}
+
+	ok, _, err := s.kv.CAS(&consul.KVPair{
+		Key:         key,
+		Value:       new,
+		ModifyIndex: modifyIndex,
+	}, (&consul.WriteOptions{}).WithContext(ctx))
+
+	return ok, err
+}
+
+// Watch streams put/delete events for every key under keyPrefix by polling
+// Consul's blocking queries
+func (s *Consul) Watch(ctx context.Context, keyPrefix string) (<-chan gatekeep.StoreEvent, error) {
+	out := make(chan gatekeep.StoreEvent)
+
+	go func() {
+		defer close(out)
+
+		var lastIndex uint64
+		seen := make(map[string]string)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			kvs, meta, err := s.kv.List(keyPrefix, (&consul.QueryOptions{
+				WaitIndex: lastIndex,
+			}).WithContext(ctx))
+			if err != nil {
+				return
+			}
+			lastIndex = meta.LastIndex
+
+			next := make(map[string]string, len(kvs))
+			for _, kv := range kvs {
+				next[kv.Key] = string(kv.Value)
+
+				if prev, ok := seen[kv.Key]; ok && prev == next[kv.Key] {
+					continue
+				}
+
+				select {
+				case out <- gatekeep.StoreEvent{Key: kv.Key, Value: kv.Value, Type: gatekeep.StoreEventPut}:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			for key := range seen {
+				if _, ok := next[key]; ok {
+					continue
+				}
+
+				select {
+				case out <- gatekeep.StoreEvent{Key: key, Type: gatekeep.StoreEventDelete}:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			seen = next
+		}
+	}()
+
+	return out, nil
+}
+
+// Iterate calls fn for every key under prefix (all keys if prefix is empty)
+func (s *Consul) Iterate(ctx context.Context, prefix string, fn func(key string, v []byte) error) error {
+	kvs, _, err := s.kv.List(prefix, (&consul.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range kvs {
+		if err = fn(kv.Key, kv.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Lease requests a TTL-bound Consul session to use as a lease handle; the
+// caller is responsible for renewing it (session.Renew) for as long as it's
+// needed
+func (s *Consul) Lease(ctx context.Context, ttl time.Duration) (gatekeep.LeaseID, error) {
+	id, _, err := s.cl.Session().CreateNoChecks(&consul.SessionEntry{
+		TTL:      ttl.String(),
+		Behavior: consul.SessionBehaviorDelete,
+	}, (&consul.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	return gatekeep.LeaseID(id), nil
+}
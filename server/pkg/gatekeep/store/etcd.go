@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cortezaproject/corteza/server/pkg/gatekeep"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Etcd is a gatekeep.DistributedStore backed by an etcd cluster
+//
+// GetValue/SetValue/DeleteValue map directly onto a single key,
+// CompareAndSwap guards against a racing node with an etcd transaction, and
+// Watch relays etcd's own watch stream so every node observes the same
+// lock/unlock events.
+type Etcd struct {
+	cl *clientv3.Client
+}
+
+// NewEtcd wraps an already-connected etcd client as a gatekeep.DistributedStore
+func NewEtcd(cl *clientv3.Client) *Etcd {
+	return &Etcd{cl: cl}
+}
+
+func (s *Etcd) GetValue(ctx context.Context, key string) ([]byte, error) {
+	rsp, err := s.cl.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rsp.Kvs) == 0 {
+		return nil, errNotFound
+	}
+
+	return rsp.Kvs[0].Value, nil
+}
+
+func (s *Etcd) SetValue(ctx context.Context, key string, v []byte) error {
+	_, err := s.cl.Put(ctx, key, string(v))
+	return err
+}
+
+func (s *Etcd) DeleteValue(ctx context.Context, key string) error {
+	_, err := s.cl.Delete(ctx, key)
+	return err
+}
+
+// CompareAndSwap replaces expect with new for key, succeeding only if key's
+// current value still matches expect; an empty expect means "key must not
+// exist yet".
+func (s *Etcd) CompareAndSwap(ctx context.Context, key string, expect, new []byte) (bool, error) {
+	var cmp clientv3.Cmp
+	if len(expect) == 0 {
+		cmp = clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	} else {
+		cmp = clientv3.Compare(clientv3.Value(key), "=", string(expect))
+	}
+
+	rsp, err := s.cl.Txn(ctx).
+		If(cmp).
+		Then(clientv3.OpPut(key, string(new))).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return rsp.Succeeded, nil
+}
+
+// Watch streams put/delete events for every key under keyPrefix
+func (s *Etcd) Watch(ctx context.Context, keyPrefix string) (<-chan gatekeep.StoreEvent, error) {
+	out := make(chan gatekeep.StoreEvent)
+	wc := s.cl.Watch(ctx, keyPrefix, clientv3.WithPrefix())
+
+	go func() {
+		defer close(out)
+
+		for rsp := range wc {
+			for _, evt := range rsp.Events {
+				se := gatekeep.StoreEvent{Key: string(evt.Kv.Key)}
+
+				if evt.Type == clientv3.EventTypeDelete {
+					se.Type = gatekeep.StoreEventDelete
+				} else {
+					se.Type = gatekeep.StoreEventPut
+					se.Value = evt.Kv.Value
+				}
+
+				select {
+				case out <- se:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Iterate calls fn for every key under prefix (all keys if prefix is empty)
+func (s *Etcd) Iterate(ctx context.Context, prefix string, fn func(key string, v []byte) error) error {
+	rsp, err := s.cl.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range rsp.Kvs {
+		if err = fn(string(kv.Key), kv.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Lease requests a TTL-bound lease from etcd; the caller is responsible for
+// keeping it alive (via the client's KeepAlive) for as long as it needs it
+func (s *Etcd) Lease(ctx context.Context, ttl time.Duration) (gatekeep.LeaseID, error) {
+	rsp, err := s.cl.Grant(ctx, int64(ttl.Seconds()))
+	if err != nil {
+		return "", err
+	}
+
+	return gatekeep.LeaseID(strconv.FormatInt(int64(rsp.ID), 10)), nil
+}
+
+var errNotFound = fmt.Errorf("not found")